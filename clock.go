@@ -0,0 +1,84 @@
+package yellow
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a Clock whose time only moves when Advance is called,
+// similar to jonboulle/clockwork's FakeClock. It's meant for tests
+// that would otherwise need to sleep on real timers and race against
+// <-ch to observe a Deadline firing.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeTimer
+}
+
+// NewFakeClock returns a FakeClock set to a fixed, arbitrary time: the
+// moment Go 1.0 was released.
+func NewFakeClock() *FakeClock {
+	return &FakeClock{now: time.Date(2012, time.March, 28, 0, 0, 0, 0, time.UTC)}
+}
+
+// Now satisfies Clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc satisfies Clock. f is invoked, synchronously and from the
+// goroutine calling Advance, once the clock has been advanced to or
+// past its deadline.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, when: c.now.Add(d), f: f}
+	c.waiters = append(c.waiters, t)
+	return t
+}
+
+// Advance moves the clock forward by d, synchronously firing any
+// AfterFunc callbacks whose deadline has now elapsed.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	var fire []*fakeTimer
+	remaining := c.waiters[:0]
+	for _, t := range c.waiters {
+		if t.stopped {
+			continue
+		}
+		if !t.when.After(now) {
+			t.fired = true
+			fire = append(fire, t)
+		} else {
+			remaining = append(remaining, t)
+		}
+	}
+	c.waiters = remaining
+	c.mu.Unlock()
+
+	for _, t := range fire {
+		t.f()
+	}
+}
+
+type fakeTimer struct {
+	clock   *FakeClock
+	when    time.Time
+	f       func()
+	stopped bool
+	fired   bool
+}
+
+// Stop satisfies Timer.
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasPending := !t.stopped && !t.fired
+	t.stopped = true
+	return wasPending
+}
@@ -0,0 +1,72 @@
+package yellow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockAfterFunc(t *testing.T) {
+	clk := NewFakeClock()
+	fired := false
+	clk.AfterFunc(time.Second, func() { fired = true })
+
+	clk.Advance(500 * time.Millisecond)
+	if fired {
+		t.Fatalf("fired before its deadline")
+	}
+
+	clk.Advance(500 * time.Millisecond)
+	if !fired {
+		t.Fatalf("expected AfterFunc to fire once its deadline elapsed")
+	}
+}
+
+func TestFakeClockAfterFuncStop(t *testing.T) {
+	clk := NewFakeClock()
+	fired := false
+	timer := clk.AfterFunc(time.Second, func() { fired = true })
+
+	if !timer.Stop() {
+		t.Errorf("expected Stop to report the timer as pending")
+	}
+	if timer.Stop() {
+		t.Errorf("expected a second Stop to report the timer as already stopped")
+	}
+
+	clk.Advance(time.Minute)
+	if fired {
+		t.Errorf("stopped timer should not have fired")
+	}
+}
+
+func TestFakeClockNow(t *testing.T) {
+	clk := NewFakeClock()
+	start := clk.Now()
+	clk.Advance(time.Hour)
+	if got := clk.Now().Sub(start); got != time.Hour {
+		t.Errorf("expected Now to advance by an hour, got %v", got)
+	}
+}
+
+func TestDeadlineWithClock(t *testing.T) {
+	clk := NewFakeClock()
+	ch := mkChanTimedOutHandler()
+	s := DeadlineWithClock(clk, time.Second, ch)
+
+	clk.Advance(999 * time.Millisecond)
+	select {
+	case <-ch.ch:
+		t.Fatalf("fired before its deadline")
+	default:
+	}
+
+	clk.Advance(time.Millisecond)
+	ch.Wait()
+	s.Done()
+}
+
+func TestDeadlineLogWithClock(t *testing.T) {
+	clk := NewFakeClock()
+	defer DeadlineLogWithClock(clk, time.Minute, "doing a thing").Done()
+	clk.Advance(time.Second)
+}
@@ -0,0 +1,224 @@
+// Package deadliner coalesces a large number of in-flight deadlines
+// behind a single goroutine and a single timer.
+//
+// yellow's Deadline spawns one time.AfterFunc (and, transitively, one
+// goroutine) per call, which gets expensive at high call rates. A
+// Deadliner lets callers share a single scheduler instead, at the
+// cost of delivering expirations through a channel rather than a
+// callback.
+//
+// Example:
+//
+//	dl := deadliner.New()
+//	defer dl.Close()
+//	go func() {
+//	    for e := range dl.C() {
+//	        log.Printf("request %v took too long", e.Key)
+//	    }
+//	}()
+//	dl.Add(requestID, time.Now().Add(time.Second))
+package deadliner
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Expired is delivered on a Deadliner's channel when a previously
+// Added key's deadline elapses without being canceled or rescheduled.
+type Expired struct {
+	Key      any
+	Deadline time.Time
+}
+
+// item is an entry in the heap, also indexed by key so Add/Remove can
+// find and fix it up in O(log n).
+type item struct {
+	key      any
+	deadline time.Time
+	index    int
+}
+
+type itemHeap []*item
+
+func (h itemHeap) Len() int           { return len(h) }
+func (h itemHeap) Less(i, j int) bool { return h[i].deadline.Before(h[j].deadline) }
+func (h itemHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *itemHeap) Push(x any) {
+	it := x.(*item)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *itemHeap) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	it.index = -1
+	*h = old[:n-1]
+	return it
+}
+
+// Deadliner tracks a (potentially large) set of pending deadlines
+// keyed by a caller-supplied key, emitting each key on C as its
+// deadline elapses. A single goroutine and a single time.Timer are
+// shared across every key, however many are pending.
+type Deadliner struct {
+	mu    sync.Mutex
+	items itemHeap
+	byKey map[any]*item
+
+	kick      chan struct{}
+	out       chan Expired
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// New starts a Deadliner. Callers should Close it when it's no
+// longer needed, to stop the background goroutine.
+func New() *Deadliner {
+	d := &Deadliner{
+		byKey:   make(map[any]*item),
+		kick:    make(chan struct{}, 1),
+		out:     make(chan Expired),
+		closing: make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Add schedules key to be emitted on C at deadline, canceling and
+// replacing any deadline previously scheduled for key.
+//
+// Add returns false, without scheduling anything, if key has no
+// existing schedule and deadline has already passed.
+func (d *Deadliner) Add(key any, deadline time.Time) bool {
+	d.mu.Lock()
+	it, exists := d.byKey[key]
+	if !exists && !deadline.After(time.Now()) {
+		d.mu.Unlock()
+		return false
+	}
+	if exists {
+		it.deadline = deadline
+		heap.Fix(&d.items, it.index)
+	} else {
+		it = &item{key: key, deadline: deadline}
+		heap.Push(&d.items, it)
+		d.byKey[key] = it
+	}
+	d.mu.Unlock()
+	d.wake()
+	return true
+}
+
+// Remove cancels key's pending deadline, if any, returning whether it
+// was found and removed.
+func (d *Deadliner) Remove(key any) bool {
+	d.mu.Lock()
+	it, ok := d.byKey[key]
+	if !ok {
+		d.mu.Unlock()
+		return false
+	}
+	heap.Remove(&d.items, it.index)
+	delete(d.byKey, key)
+	d.mu.Unlock()
+	d.wake()
+	return true
+}
+
+// C returns the channel on which expired keys are delivered.
+func (d *Deadliner) C() <-chan Expired {
+	return d.out
+}
+
+// Close stops the Deadliner's background goroutine and closes the
+// channel returned by C. It is safe to call more than once.
+func (d *Deadliner) Close() {
+	d.closeOnce.Do(func() { close(d.closing) })
+}
+
+// wake nudges run to recompute its next wakeup after Add or Remove
+// changes the heap. It never blocks: a pending, undelivered kick is
+// just as good as a new one.
+func (d *Deadliner) wake() {
+	select {
+	case d.kick <- struct{}{}:
+	default:
+	}
+}
+
+// run is the Deadliner's single long-lived goroutine. It alone owns
+// the timer, so it's the only goroutine that ever calls Reset or
+// Stop on it, sidestepping the usual races around resetting timers
+// from multiple goroutines.
+func (d *Deadliner) run() {
+	timer := time.NewTimer(time.Hour)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	for {
+		d.mu.Lock()
+		hasNext := len(d.items) > 0
+		var waitC <-chan time.Time
+		if hasNext {
+			next := time.Until(d.items[0].deadline)
+			if next <= 0 {
+				d.mu.Unlock()
+				d.fireExpired()
+				continue
+			}
+			timer.Reset(next)
+			waitC = timer.C
+		}
+		d.mu.Unlock()
+
+		select {
+		case <-d.closing:
+			close(d.out)
+			return
+		case <-d.kick:
+			if hasNext && !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		case <-waitC:
+			d.fireExpired()
+		}
+	}
+}
+
+// fireExpired pops and delivers every item whose deadline has
+// elapsed. It's only ever called from run, so sends on d.out can't
+// race with run closing it.
+func (d *Deadliner) fireExpired() {
+	d.mu.Lock()
+	now := time.Now()
+	var fired []Expired
+	for len(d.items) > 0 && !d.items[0].deadline.After(now) {
+		it := heap.Pop(&d.items).(*item)
+		delete(d.byKey, it.key)
+		fired = append(fired, Expired{Key: it.key, Deadline: it.deadline})
+	}
+	d.mu.Unlock()
+
+	for _, e := range fired {
+		select {
+		case d.out <- e:
+		case <-d.closing:
+			return
+		}
+	}
+}
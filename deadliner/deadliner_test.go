@@ -0,0 +1,113 @@
+package deadliner
+
+import (
+	"testing"
+	"time"
+)
+
+func drain(t *testing.T, d *Deadliner, want ...any) {
+	t.Helper()
+	got := map[any]bool{}
+	for range want {
+		select {
+		case e := <-d.C():
+			got[e.Key] = true
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for expirations, got %v so far", got)
+		}
+	}
+	for _, k := range want {
+		if !got[k] {
+			t.Errorf("expected %v to have expired, got %v", k, got)
+		}
+	}
+}
+
+func TestAddExpires(t *testing.T) {
+	d := New()
+	defer d.Close()
+
+	if !d.Add("a", time.Now().Add(time.Millisecond)) {
+		t.Fatalf("expected Add to succeed")
+	}
+	drain(t, d, "a")
+}
+
+func TestAddPastDeadline(t *testing.T) {
+	d := New()
+	defer d.Close()
+
+	if d.Add("a", time.Now().Add(-time.Second)) {
+		t.Errorf("expected Add with a past deadline to return false")
+	}
+	select {
+	case e := <-d.C():
+		t.Fatalf("didn't expect an expiration, got %v", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestAddExistingKeyReschedules(t *testing.T) {
+	d := New()
+	defer d.Close()
+
+	d.Add("a", time.Now().Add(time.Hour))
+	if !d.Add("a", time.Now().Add(time.Millisecond)) {
+		t.Fatalf("expected rescheduling an existing key to succeed")
+	}
+	drain(t, d, "a")
+}
+
+func TestRemove(t *testing.T) {
+	d := New()
+	defer d.Close()
+
+	d.Add("a", time.Now().Add(10*time.Millisecond))
+	if !d.Remove("a") {
+		t.Errorf("expected Remove to find the key")
+	}
+	if d.Remove("a") {
+		t.Errorf("expected a second Remove to find nothing")
+	}
+	select {
+	case e := <-d.C():
+		t.Fatalf("removed key still expired: %v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestManyKeysOrderedByDeadline(t *testing.T) {
+	d := New()
+	defer d.Close()
+
+	now := time.Now()
+	d.Add("c", now.Add(30*time.Millisecond))
+	d.Add("a", now.Add(10*time.Millisecond))
+	d.Add("b", now.Add(20*time.Millisecond))
+
+	for _, want := range []any{"a", "b", "c"} {
+		select {
+		case e := <-d.C():
+			if e.Key != want {
+				t.Errorf("expected %v next, got %v", want, e.Key)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %v", want)
+		}
+	}
+}
+
+func TestCloseStopsDelivery(t *testing.T) {
+	d := New()
+	d.Add("a", time.Now().Add(time.Hour))
+	d.Close()
+
+	select {
+	case _, ok := <-d.C():
+		if ok {
+			t.Errorf("expected C to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for C to close")
+	}
+}
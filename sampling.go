@@ -0,0 +1,198 @@
+package yellow
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// SampledHandler wraps inner so that only a random fraction of events
+// (rate, in [0,1]) are forwarded to it. This lets operators keep
+// yellow enabled in a hot path without a firehose of "taking too
+// long" logs during an incident.
+//
+// If inner also satisfies TimedOutHandler, the returned Handler does
+// too, with TimedOut events sampled independently of Completed ones.
+func SampledHandler(inner Handler, rate float64) Handler {
+	base := &sampledHandler{inner: inner, rate: rate}
+	if th, ok := inner.(TimedOutHandler); ok {
+		return sampledTimedOutHandler{sampledHandler: base, timedOut: th}
+	}
+	return base
+}
+
+type sampledHandler struct {
+	inner Handler
+	rate  float64
+}
+
+func (h *sampledHandler) fire() bool {
+	return rand.Float64() < h.rate
+}
+
+// Completed satisfies Handler.
+func (h *sampledHandler) Completed(t time.Time) {
+	if h.fire() {
+		h.inner.Completed(t)
+	}
+}
+
+type sampledTimedOutHandler struct {
+	*sampledHandler
+	timedOut TimedOutHandler
+}
+
+// TimedOut satisfies TimedOutHandler.
+func (h sampledTimedOutHandler) TimedOut(t time.Time) {
+	if h.fire() {
+		h.timedOut.TimedOut(t)
+	}
+}
+
+// RateLimitedHandler wraps inner so that at most perSecond events are
+// forwarded to it per second; the rest are dropped. Like
+// SampledHandler, this composes with the existing logHandler and
+// logWarningHandler without any change to Stopwatch.
+//
+// If inner also satisfies TimedOutHandler, the returned Handler does
+// too, sharing the same per-second budget with Completed.
+func RateLimitedHandler(inner Handler, perSecond int) Handler {
+	base := &rateLimitedHandler{inner: inner, perSecond: perSecond, clock: defaultClock}
+	if th, ok := inner.(TimedOutHandler); ok {
+		return rateLimitedTimedOutHandler{rateLimitedHandler: base, timedOut: th}
+	}
+	return base
+}
+
+type rateLimitedHandler struct {
+	inner     Handler
+	perSecond int
+	clock     Clock
+
+	mu         sync.Mutex
+	windowFrom time.Time
+	count      int
+}
+
+func (h *rateLimitedHandler) allow() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.clock.Now()
+	if now.Sub(h.windowFrom) >= time.Second {
+		h.windowFrom = now
+		h.count = 0
+	}
+	if h.count >= h.perSecond {
+		return false
+	}
+	h.count++
+	return true
+}
+
+// Completed satisfies Handler.
+func (h *rateLimitedHandler) Completed(t time.Time) {
+	if h.allow() {
+		h.inner.Completed(t)
+	}
+}
+
+type rateLimitedTimedOutHandler struct {
+	*rateLimitedHandler
+	timedOut TimedOutHandler
+}
+
+// TimedOut satisfies TimedOutHandler.
+func (h rateLimitedTimedOutHandler) TimedOut(t time.Time) {
+	if h.allow() {
+		h.timedOut.TimedOut(t)
+	}
+}
+
+// DeadlineAdjuster is implemented by handlers, such as the one
+// returned by NewAdaptiveHandler, that can suggest a different
+// deadline for the caller's next Deadline call based on their own
+// recent history.
+type DeadlineAdjuster interface {
+	// NextDeadline returns the deadline callers should use next,
+	// given that they were about to use base.
+	NextDeadline(base time.Duration) time.Duration
+}
+
+// NewAdaptiveHandler wraps inner so that once it's firing more than
+// threshold times per second, events stop being forwarded to it and
+// NextDeadline starts suggesting a longer deadline, so a caller
+// re-arming its Deadline call in a loop backs off automatically
+// instead of flooding logs during a latency incident.
+//
+// If inner also satisfies TimedOutHandler, the returned Handler does
+// too.
+func NewAdaptiveHandler(inner Handler, threshold int) Handler {
+	base := &adaptiveHandler{inner: inner, threshold: threshold, clock: defaultClock}
+	if th, ok := inner.(TimedOutHandler); ok {
+		return adaptiveTimedOutHandler{adaptiveHandler: base, timedOut: th}
+	}
+	return base
+}
+
+type adaptiveHandler struct {
+	inner     Handler
+	threshold int
+	clock     Clock
+
+	mu         sync.Mutex
+	windowFrom time.Time
+	count      int
+}
+
+// record counts this event towards the current one-second window,
+// resetting the window if it has elapsed, and reports whether the
+// window is (still) within threshold.
+func (h *adaptiveHandler) record() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.clock.Now()
+	if now.Sub(h.windowFrom) >= time.Second {
+		h.windowFrom = now
+		h.count = 0
+	}
+	h.count++
+	return h.count <= h.threshold
+}
+
+// overThreshold reports whether the current window has already
+// exceeded threshold, without counting a new event towards it.
+func (h *adaptiveHandler) overThreshold() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clock.Now().Sub(h.windowFrom) >= time.Second {
+		return false
+	}
+	return h.count > h.threshold
+}
+
+// NextDeadline satisfies DeadlineAdjuster.
+func (h *adaptiveHandler) NextDeadline(base time.Duration) time.Duration {
+	if h.overThreshold() {
+		return base * 2
+	}
+	return base
+}
+
+// Completed satisfies Handler.
+func (h *adaptiveHandler) Completed(t time.Time) {
+	if h.record() {
+		h.inner.Completed(t)
+	}
+}
+
+type adaptiveTimedOutHandler struct {
+	*adaptiveHandler
+	timedOut TimedOutHandler
+}
+
+// TimedOut satisfies TimedOutHandler.
+func (h adaptiveTimedOutHandler) TimedOut(t time.Time) {
+	if h.record() {
+		h.timedOut.TimedOut(t)
+	}
+}
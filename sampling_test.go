@@ -0,0 +1,99 @@
+package yellow
+
+import (
+	"testing"
+	"time"
+)
+
+type countingHandler struct {
+	completed int
+	timedOut  int
+}
+
+func (h *countingHandler) Completed(time.Time) { h.completed++ }
+func (h *countingHandler) TimedOut(time.Time)  { h.timedOut++ }
+
+func TestSampledHandlerZeroRateDropsEverything(t *testing.T) {
+	inner := &countingHandler{}
+	h := SampledHandler(inner, 0)
+	for i := 0; i < 100; i++ {
+		h.Completed(time.Now())
+	}
+	if inner.completed != 0 {
+		t.Errorf("expected a zero rate to drop everything, got %d completions", inner.completed)
+	}
+}
+
+func TestSampledHandlerFullRateKeepsEverything(t *testing.T) {
+	inner := &countingHandler{}
+	h := SampledHandler(inner, 1)
+	th, ok := h.(TimedOutHandler)
+	if !ok {
+		t.Fatalf("expected SampledHandler to forward TimedOutHandler when inner has it")
+	}
+	for i := 0; i < 100; i++ {
+		h.Completed(time.Now())
+		th.TimedOut(time.Now())
+	}
+	if inner.completed != 100 || inner.timedOut != 100 {
+		t.Errorf("expected a rate of 1 to keep everything, got %d/%d", inner.completed, inner.timedOut)
+	}
+}
+
+func TestSampledHandlerNoTimedOutHandler(t *testing.T) {
+	inner := &chanHandler{ch: make(chan bool, 1)}
+	h := SampledHandler(inner, 1)
+	if _, ok := h.(TimedOutHandler); ok {
+		t.Errorf("expected SampledHandler not to satisfy TimedOutHandler when inner doesn't")
+	}
+}
+
+func TestRateLimitedHandlerCapsPerSecond(t *testing.T) {
+	inner := &countingHandler{}
+	clk := NewFakeClock()
+	h := &rateLimitedHandler{inner: inner, perSecond: 3, clock: clk}
+	for i := 0; i < 10; i++ {
+		h.Completed(time.Now())
+	}
+	if inner.completed != 3 {
+		t.Errorf("expected 3 completions within the first second, got %d", inner.completed)
+	}
+
+	clk.Advance(time.Second)
+	h.Completed(time.Now())
+	if inner.completed != 4 {
+		t.Errorf("expected the budget to refill in the next window, got %d", inner.completed)
+	}
+}
+
+func TestAdaptiveHandlerBacksOffOverThreshold(t *testing.T) {
+	inner := &countingHandler{}
+	clk := NewFakeClock()
+	h := &adaptiveHandler{inner: inner, threshold: 2, clock: clk}
+
+	h.Completed(time.Now())
+	h.Completed(time.Now())
+	if got := h.NextDeadline(time.Second); got != time.Second {
+		t.Errorf("expected no backoff at threshold, got %v", got)
+	}
+
+	h.Completed(time.Now())
+	if inner.completed != 2 {
+		t.Errorf("expected the 3rd event over threshold to be dropped, got %d completions", inner.completed)
+	}
+	if got := h.NextDeadline(time.Second); got != 2*time.Second {
+		t.Errorf("expected NextDeadline to back off once over threshold, got %v", got)
+	}
+
+	clk.Advance(time.Second)
+	if got := h.NextDeadline(time.Second); got != time.Second {
+		t.Errorf("expected the backoff to clear once the window rolls over, got %v", got)
+	}
+}
+
+func TestNewAdaptiveHandlerImplementsDeadlineAdjuster(t *testing.T) {
+	h := NewAdaptiveHandler(&countingHandler{}, 10)
+	if _, ok := h.(DeadlineAdjuster); !ok {
+		t.Errorf("expected NewAdaptiveHandler's result to satisfy DeadlineAdjuster")
+	}
+}
@@ -0,0 +1,53 @@
+package yellow
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SlogHandler returns a Handler (which also satisfies TimedOutHandler)
+// that logs Completed and TimedOut events as structured slog records
+// instead of log.Printf string concatenation. Each record carries a
+// duration attr (time elapsed since started), a started attr, and any
+// user-supplied attrs. TimedOut records additionally carry an err attr
+// wrapping ErrDeadlineExceeded.
+func SlogHandler(logger *slog.Logger, msg string, attrs ...slog.Attr) Handler {
+	return slogHandler{logger: logger, msg: msg, attrs: attrs}
+}
+
+type slogHandler struct {
+	logger *slog.Logger
+	msg    string
+	attrs  []slog.Attr
+	clock  Clock
+}
+
+func (h slogHandler) clockOrDefault() Clock {
+	if h.clock != nil {
+		return h.clock
+	}
+	return defaultClock
+}
+
+func (h slogHandler) log(level slog.Level, started time.Time, err error) {
+	now := h.clockOrDefault().Now()
+	attrs := make([]slog.Attr, 0, len(h.attrs)+3)
+	attrs = append(attrs, slog.Time("started", started), slog.Duration("duration", now.Sub(started)))
+	if err != nil {
+		attrs = append(attrs, slog.Any("err", err))
+	}
+	attrs = append(attrs, h.attrs...)
+	h.logger.LogAttrs(context.Background(), level, h.msg, attrs...)
+}
+
+// Completed satisfies Handler.
+func (h slogHandler) Completed(started time.Time) {
+	h.log(slog.LevelInfo, started, nil)
+}
+
+// TimedOut satisfies TimedOutHandler.
+func (h slogHandler) TimedOut(started time.Time) {
+	h.log(slog.LevelWarn, started, fmt.Errorf("%s: %w", h.msg, ErrDeadlineExceeded))
+}
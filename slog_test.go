@@ -0,0 +1,50 @@
+package yellow
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogHandlerCompleted(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	h := SlogHandler(logger, "getting thing", slog.String("thing", "widget"))
+
+	h.Completed(time.Now().Add(-time.Second))
+
+	out := buf.String()
+	for _, want := range []string{"getting thing", "duration=", "started=", "thing=widget"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected log line to contain %q, got %q", want, out)
+		}
+	}
+}
+
+func TestSlogHandlerTimedOut(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	h := SlogHandler(logger, "getting thing")
+
+	th, ok := h.(TimedOutHandler)
+	if !ok {
+		t.Fatalf("expected SlogHandler to satisfy TimedOutHandler")
+	}
+	th.TimedOut(time.Now())
+
+	out := buf.String()
+	if !strings.Contains(out, "level=WARN") {
+		t.Errorf("expected a warning-level record, got %q", out)
+	}
+	if !strings.Contains(out, "deadline exceeded") {
+		t.Errorf("expected the err attr to mention the deadline, got %q", out)
+	}
+}
+
+func TestDeadlineWithSlogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	defer Deadline(time.Minute, SlogHandler(logger, "quick thing")).Done()
+}
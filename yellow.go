@@ -3,11 +3,11 @@
 //
 // Example:
 //
-//   func ShouldBeFast(thing, place string) {
-//       defer yellow.DeadlineLog(time.Second,
-//           "getting %q from %v", thing, place).Done()
-//       doThing(thing, place)
-//   }
+//	func ShouldBeFast(thing, place string) {
+//	    defer yellow.DeadlineLog(time.Second,
+//	        "getting %q from %v", thing, place).Done()
+//	    doThing(thing, place)
+//	}
 //
 // If your handler also implements TimedOutHandler,
 // TimedOut(time.Time) will be delivered to your Handler while the
@@ -18,10 +18,22 @@
 package yellow
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 )
 
+// ErrDeadlineExceeded is the error passed (wrapped) to ErrorHandler's
+// TimedOutErr when a Stopwatch's deadline elapses before Done is
+// called. Following Go 1.15's unification of net/os timeout errors
+// behind os.ErrDeadlineExceeded, this gives callers a canonical way to
+// detect "the yellow deadline fired" with errors.Is when composing
+// handlers that also handle real I/O timeouts.
+var ErrDeadlineExceeded = errors.New("yellow: deadline exceeded")
+
 // Handler receives notifications when tasks complete after exceeding
 // their deadlines.
 type Handler interface {
@@ -50,12 +62,73 @@ type TimedOutHandler interface {
 	TimedOut(started time.Time)
 }
 
+// ErrorHandler receives the same notification as TimedOutHandler, but
+// as an error satisfying errors.Is(err, ErrDeadlineExceeded) rather
+// than a bare time.Time. This composes better with code that also
+// handles real I/O timeouts through their own errors.
+type ErrorHandler interface {
+	Handler
+	// TimedOutErr is called when your Deadline has exceeded, with err
+	// wrapping ErrDeadlineExceeded.
+	TimedOutErr(started time.Time, err error)
+}
+
+// CanceledHandler receives a notification when the context.Context
+// backing a DeadlineContext Stopwatch is canceled before Done is
+// called. When a handler implements this interface, Canceled is
+// delivered instead of Completed or TimedOut for that invocation.
+type CanceledHandler interface {
+	Handler
+	// Canceled is called when the context is done (canceled or its
+	// own deadline exceeded) before Done is invoked. err is the
+	// context's Err().
+	Canceled(started time.Time, err error)
+}
+
+// Timer is the subset of *time.Timer that Clock.AfterFunc needs to
+// return.
+type Timer interface {
+	// Stop prevents the Timer from firing, returning true if the
+	// call stops the timer, false if the timer has already expired
+	// or been stopped.
+	Stop() bool
+}
+
+// Clock abstracts the passage of time so that Deadline and Stopwatch
+// can be driven deterministically in tests. Production code should
+// leave it to the default, real-time Clock; tests can substitute a
+// FakeClock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// AfterFunc schedules f to run after d elapses, as time.AfterFunc does.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// defaultClock is the real-time Clock used by Deadline, DeadlineLog,
+// and DeadlineLogWarn.
+var defaultClock Clock = realClock{}
+
 // Stopwatch manages a timer that runs while waiting for a deadline.
 type Stopwatch struct {
 	handler Handler
 	started time.Time
 	d       time.Duration
-	t       *time.Timer
+	t       Timer
+	clock   Clock
+
+	ctx         context.Context
+	cancel      context.CancelFunc
+	cancelWatch context.CancelFunc
+	canceled    atomic.Bool
 }
 
 // Done allows the caller to indicate the Deadlined function has completed.
@@ -63,8 +136,17 @@ func (d *Stopwatch) Done() {
 	if d == nil {
 		return
 	}
+	if d.cancelWatch != nil {
+		d.cancelWatch()
+	}
+	if d.cancel != nil {
+		d.cancel()
+	}
+	if d.canceled.Load() {
+		return
+	}
 	if d.t == nil {
-		if time.Since(d.started) > d.d {
+		if d.clock.Now().Sub(d.started) > d.d {
 			d.handler.Completed(d.started)
 		}
 	} else {
@@ -74,6 +156,24 @@ func (d *Stopwatch) Done() {
 	}
 }
 
+// Context returns a context.Context derived from the Stopwatch's
+// deadline: it is canceled when the deadline elapses or when Done is
+// called, whichever comes first. This lets callers propagate the same
+// slow-call deadline into downstream RPCs the way net.Dialer.DialContext
+// propagates deadlines.
+//
+// For a Stopwatch created with DeadlineContext, the returned context
+// is also canceled if the original context is canceled.
+func (d *Stopwatch) Context() context.Context {
+	if d == nil {
+		return context.Background()
+	}
+	if d.ctx == nil {
+		d.ctx, d.cancel = context.WithDeadline(context.Background(), d.started.Add(d.d))
+	}
+	return d.ctx
+}
+
 // Deadline sets up a Handler to be notified if Done isn't called
 // before the requested timeout occurs.
 //
@@ -84,25 +184,115 @@ func (d *Stopwatch) Done() {
 // is possible to receive a notification that your function is running
 // slowly after it's completed (late).
 func Deadline(d time.Duration, handler Handler) *Stopwatch {
+	return DeadlineWithClock(defaultClock, d, handler)
+}
+
+// DeadlineWithClock is like Deadline, but obtains the current time
+// and schedules its timer through clk instead of the real clock. This
+// is mainly useful for tests that want to drive a Stopwatch with a
+// FakeClock instead of waiting on real time.
+func DeadlineWithClock(clk Clock, d time.Duration, handler Handler) *Stopwatch {
 	if d == 0 {
 		return nil
 	}
-	rv := &Stopwatch{handler, time.Now(), d, nil}
-	if h, ok := handler.(TimedOutHandler); ok {
-		rv.t = time.AfterFunc(d, func() { h.TimedOut(rv.started) })
+	rv := &Stopwatch{handler: handler, started: clk.Now(), d: d, clock: clk}
+	rv.t = scheduleTimedOut(clk, d, rv.started, handler)
+	return rv
+}
+
+// scheduleTimedOut schedules handler's TimedOut/TimedOutErr callbacks
+// (whichever it implements) to fire after d, returning nil if handler
+// implements neither.
+func scheduleTimedOut(clk Clock, d time.Duration, started time.Time, handler Handler) Timer {
+	th, isTimedOut := handler.(TimedOutHandler)
+	eh, isError := handler.(ErrorHandler)
+	if !isTimedOut && !isError {
+		return nil
+	}
+	return clk.AfterFunc(d, func() {
+		if isTimedOut {
+			th.TimedOut(started)
+		}
+		if isError {
+			eh.TimedOutErr(started, fmt.Errorf("yellow: deadline of %s exceeded: %w", d, ErrDeadlineExceeded))
+		}
+	})
+}
+
+// DeadlineContext is like Deadline, but ties the Stopwatch to a
+// context.Context.
+//
+// If ctx is canceled before Done is called, the Stopwatch's timer is
+// stopped and, if handler also satisfies CanceledHandler, Canceled is
+// delivered instead of TimedOut/Completed.
+func DeadlineContext(ctx context.Context, d time.Duration, handler Handler) *Stopwatch {
+	if d == 0 {
+		return nil
 	}
+	rv := &Stopwatch{handler: handler, started: time.Now(), d: d, clock: defaultClock}
+	rv.ctx, rv.cancel = context.WithDeadline(ctx, rv.started.Add(d))
+	rv.t = scheduleTimedOut(defaultClock, d, rv.started, handler)
+	h, _ := handler.(CanceledHandler)
+	watchCtx, watchCancel := context.WithCancel(context.Background())
+	rv.cancelWatch = watchCancel
+	go rv.watchCanceled(ctx, watchCtx, h)
 	return rv
 }
 
+// watchCanceled waits for either parent to finish (in which case the
+// caller's context was canceled out from under us) or watchCtx to
+// finish (in which case Done was called normally and there's nothing
+// to report). h is nil when the handler isn't a CanceledHandler.
+func (d *Stopwatch) watchCanceled(parent, watchCtx context.Context, h CanceledHandler) {
+	select {
+	case <-parent.Done():
+		if d.canceled.CompareAndSwap(false, true) {
+			if d.t != nil {
+				d.t.Stop()
+			}
+			if h != nil {
+				h.Canceled(d.started, parent.Err())
+			}
+		}
+	case <-watchCtx.Done():
+	}
+}
+
 // DeadlineLog is a convenience invocation of Deadline that just logs completion events.
 func DeadlineLog(d time.Duration, format string, args ...interface{}) *Stopwatch {
-	return Deadline(d, logHandler{format, args})
+	return Deadline(d, logHandler{format: format, args: args})
 }
 
 // DeadlineLogWarn is a convenience invocation of Deadline that logs
 // completion events as well as "taking too long" events.
 func DeadlineLogWarn(d time.Duration, format string, args ...interface{}) *Stopwatch {
-	return Deadline(d, logWarningHandler{logHandler{format, args}})
+	return Deadline(d, logWarningHandler{logHandler{format: format, args: args}})
+}
+
+// DeadlineLogWithClock is a convenience invocation of
+// DeadlineWithClock that just logs completion events, timed against clk.
+func DeadlineLogWithClock(clk Clock, d time.Duration, format string, args ...interface{}) *Stopwatch {
+	return DeadlineWithClock(clk, d, logHandler{format: format, args: args, clock: clk})
+}
+
+// DeadlineLogWarnWithClock is a convenience invocation of
+// DeadlineWithClock that logs completion events as well as "taking
+// too long" events, timed against clk.
+func DeadlineLogWarnWithClock(clk Clock, d time.Duration, format string, args ...interface{}) *Stopwatch {
+	return DeadlineWithClock(clk, d, logWarningHandler{logHandler{format: format, args: args, clock: clk}})
+}
+
+// DeadlineLogContext is a convenience invocation of DeadlineContext
+// that just logs completion events.
+func DeadlineLogContext(ctx context.Context, d time.Duration, format string, args ...interface{}) *Stopwatch {
+	return DeadlineContext(ctx, d, logHandler{format: format, args: args})
+}
+
+// DeadlineLogWarnContext is a convenience invocation of
+// DeadlineContext that logs completion events as well as "taking too
+// long" events.
+func DeadlineLogWarnContext(ctx context.Context, d time.Duration, format string, args ...interface{}) *Stopwatch {
+	return DeadlineContext(ctx, d, logWarningHandler{logHandler{format: format, args: args}})
 }
 
 // LogHandler is a handler that logs handled events.
@@ -111,6 +301,15 @@ type logHandler struct {
 	format string
 	// args for the format string
 	args []interface{}
+	// clock used to compute the elapsed duration; defaultClock if nil.
+	clock Clock
+}
+
+func (l logHandler) clockOrDefault() Clock {
+	if l.clock != nil {
+		return l.clock
+	}
+	return defaultClock
 }
 
 type logWarningHandler struct {
@@ -119,10 +318,10 @@ type logWarningHandler struct {
 
 // TimedOut satisfies Handler.Timeout
 func (l logWarningHandler) TimedOut(started time.Time) {
-	log.Printf("Taking too long: "+l.format+" "+time.Since(started).String(), l.args...)
+	log.Printf("Taking too long: "+l.format+" "+l.clockOrDefault().Now().Sub(started).String(), l.args...)
 }
 
 // Completed satisfies Handler.Completed
 func (l logHandler) Completed(started time.Time) {
-	log.Printf("Finally finished: "+l.format+" "+time.Since(started).String(), l.args...)
+	log.Printf("Finally finished: "+l.format+" "+l.clockOrDefault().Now().Sub(started).String(), l.args...)
 }
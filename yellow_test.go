@@ -2,7 +2,10 @@ package yellow
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
 	"testing"
 	"time"
@@ -76,18 +79,91 @@ func TestNoTimeoutWarning(t *testing.T) {
 	defer Deadline(time.Minute, &failHandler{t}).Done()
 }
 
+type chanErrorHandler struct {
+	ch  chan bool
+	err error
+}
+
+func (n *chanErrorHandler) Completed(t time.Time) {}
+func (n *chanErrorHandler) TimedOutErr(t time.Time, err error) {
+	n.err = err
+	close(n.ch)
+}
+
+func TestErrorHandlerTimedOut(t *testing.T) {
+	h := &chanErrorHandler{ch: make(chan bool)}
+	defer Deadline(1, h).Done()
+	<-h.ch
+	if !errors.Is(h.err, ErrDeadlineExceeded) {
+		t.Errorf("expected ErrDeadlineExceeded, got %v", h.err)
+	}
+}
+
+type chanCanceledHandler struct {
+	ch  chan bool
+	err error
+}
+
+func (n *chanCanceledHandler) Completed(t time.Time) {}
+func (n *chanCanceledHandler) Canceled(t time.Time, err error) {
+	n.err = err
+	close(n.ch)
+}
+
+func TestDeadlineContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &chanCanceledHandler{ch: make(chan bool)}
+	s := DeadlineContext(ctx, time.Minute, h)
+	cancel()
+	<-h.ch
+	if !errors.Is(h.err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", h.err)
+	}
+	s.Done() // a noop once Canceled has been delivered
+}
+
+func TestDeadlineContextNotCanceled(t *testing.T) {
+	ch := mkChanHandler()
+	defer assertTimedOut(t, ch)
+	defer DeadlineContext(context.Background(), 1, ch).Done()
+	time.Sleep(time.Millisecond)
+}
+
+func TestStopwatchContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := DeadlineLogContext(ctx, time.Minute, "doing a thing")
+	sctx := s.Context()
+	select {
+	case <-sctx.Done():
+		t.Fatalf("context should not be done yet")
+	default:
+	}
+	s.Done()
+	<-sctx.Done()
+}
+
+func TestStopwatchContextNilDone(t *testing.T) {
+	var s *Stopwatch
+	if s.Context() == nil {
+		t.Errorf("expected a non-nil background context for a nil Stopwatch")
+	}
+}
+
 var (
 	_ = Handler(HandleFunc(func(time.Time) {}))
 	_ = Handler(logHandler{})
 	_ = Handler(logWarningHandler{})
 	_ = TimedOutHandler(logWarningHandler{})
+	_ = CanceledHandler((*chanCanceledHandler)(nil))
+	_ = ErrorHandler((*chanErrorHandler)(nil))
 )
 
 func TestLogger(t *testing.T) {
 	buf := &bytes.Buffer{}
 	log.SetOutput(buf)
 	defer log.SetOutput(os.Stderr)
-	lh := logWarningHandler{logHandler{"got %q", []interface{}{"x"}}}
+	lh := logWarningHandler{logHandler{format: "got %q", args: []interface{}{"x"}}}
 	lh.TimedOut(time.Now())
 	lh.Completed(time.Now())
 	// Should probably actually inspect this stuff.
@@ -313,3 +389,16 @@ func ExampleHandleFunc() {
 	// takes more than a second, update the histogram with how
 	// long it took.
 }
+
+func ExampleDeadlineContext() {
+	ctx := context.Background() // presumably from a request
+	s := DeadlineLogContext(ctx, time.Second, "calling downstream")
+	defer s.Done()
+
+	// Propagate the same deadline into a downstream RPC.
+	req, err := http.NewRequestWithContext(s.Context(), "GET", "http://example.com", nil)
+	if err != nil {
+		return
+	}
+	http.DefaultClient.Do(req)
+}